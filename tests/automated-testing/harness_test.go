@@ -0,0 +1,248 @@
+//go:build integration
+// +build integration
+
+// Package automatedtesting boots a real gaia dev-chain, tails its write
+// trace through the full tracelistener pipeline, and asserts the resulting
+// database rows. It is excluded from the default build/test run (no
+// go.mod-less CI job can bring up a chain binary and a Cockroach container)
+// and is meant to be run explicitly with `go test -tags integration ./...`
+// against a checked-out environment that has both available.
+package automatedtesting
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/allinbits/demeris-backend/tracelistener/config"
+	"github.com/allinbits/demeris-backend/tracelistener/gaia_processor"
+	"github.com/allinbits/tracelistener/tracelistener"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	gaiaBinary  = "gaiad"
+	testChainID = "tracelistener-harness"
+)
+
+// harness wires together a gaia dev-chain, a Processor reading its trace
+// store, and the Cockroach instance the writer ultimately writes to.
+type harness struct {
+	t            *testing.T
+	dir          string
+	fifoPath     string
+	gaiaCmd      *exec.Cmd
+	db           *sqlx.DB
+	processor    *gaia_processor.Processor
+	logger       *zap.SugaredLogger
+	watcherErrCh chan error
+	teardownFn   []func()
+}
+
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "tracelistener-harness")
+	require.NoError(t, err)
+
+	logger, _ := zap.NewDevelopment()
+
+	h := &harness{t: t, dir: dir, logger: logger.Sugar()}
+	h.teardownFn = append(h.teardownFn, func() { os.RemoveAll(dir) })
+
+	h.fifoPath = filepath.Join(dir, "trace.fifo")
+	require.NoError(t, syscall.Mkfifo(h.fifoPath, 0o600))
+
+	h.db = h.startCockroach()
+	h.gaiaCmd = h.startGaiaDevChain()
+	h.processor = h.startProcessor()
+	h.startTraceWatcher()
+
+	return h
+}
+
+// startTraceWatcher tails h.fifoPath and feeds every recorded operation into
+// h.processor's OpsChan, the same way gaia_processor.New would wire a
+// TraceWatcher in production. Without this, gaiad writing to the FIFO and the
+// Processor reading from writeChan are never actually connected.
+func (h *harness) startTraceWatcher() {
+	h.t.Helper()
+
+	h.watcherErrCh = make(chan error, 1)
+
+	tw := tracelistener.TraceWatcher{
+		DataSourcePath: h.fifoPath,
+		DataChan:       h.processor.OpsChan(),
+		ErrorChan:      h.watcherErrCh,
+		Logger:         h.logger,
+	}
+
+	go tw.Watch()
+}
+
+func (h *harness) Close() {
+	for i := len(h.teardownFn) - 1; i >= 0; i-- {
+		h.teardownFn[i]()
+	}
+}
+
+// startCockroach starts a single-node, in-memory Cockroach instance via
+// `cockroach start-single-node --insecure` and returns a connected *sqlx.DB.
+// A real CI environment swaps this for a pooled test container; a single
+// ephemeral node is enough for this harness.
+func (h *harness) startCockroach() *sqlx.DB {
+	h.t.Helper()
+
+	cmd := exec.Command("cockroach", "start-single-node", "--insecure", "--store=mem=1GiB", "--listen-addr=localhost:26299")
+	require.NoError(h.t, cmd.Start())
+	h.teardownFn = append(h.teardownFn, func() { _ = cmd.Process.Kill() })
+
+	var db *sqlx.DB
+	require.Eventually(h.t, func() bool {
+		var err error
+		db, err = sqlx.Connect("postgres", "postgresql://root@localhost:26299/defaultdb?sslmode=disable")
+		return err == nil
+	}, 30*time.Second, 200*time.Millisecond)
+
+	return db
+}
+
+// startGaiaDevChain starts a single-validator gaia dev-chain with
+// --trace-store pointing at the harness FIFO.
+func (h *harness) startGaiaDevChain() *exec.Cmd {
+	h.t.Helper()
+
+	cmd := exec.Command(
+		gaiaBinary, "start",
+		"--trace-store", h.fifoPath,
+		"--home", h.dir,
+		"--chain-id", testChainID,
+	)
+	require.NoError(h.t, cmd.Start())
+	h.teardownFn = append(h.teardownFn, func() { _ = cmd.Process.Kill() })
+
+	return cmd
+}
+
+// restartGaiaDevChain kills the current gaia process (simulating a crash)
+// and starts a fresh one against the same home directory, to exercise the
+// checkpoint/resume path.
+func (h *harness) restartGaiaDevChain() {
+	h.t.Helper()
+
+	_ = h.gaiaCmd.Process.Kill()
+	_, _ = h.gaiaCmd.Process.Wait()
+
+	h.gaiaCmd = h.startGaiaDevChain()
+}
+
+func (h *harness) startProcessor() *gaia_processor.Processor {
+	h.t.Helper()
+
+	cfg := &config.Config{
+		ChainName:    testChainID,
+		Checkpointer: tracelistener.NewSQLCheckpointer(h.db, tracelistener.CheckpointsTableName),
+	}
+
+	dp, err := gaia_processor.New(h.logger, cfg)
+	require.NoError(h.t, err)
+
+	p, ok := dp.(*gaia_processor.Processor)
+	require.True(h.t, ok, "gaia_processor.New must return a *gaia_processor.Processor for the harness to drive it directly")
+
+	return p
+}
+
+// TestHarness_BankSendEndsUpInBalances boots the chain, performs a bank send
+// over the SDK client, and asserts the resulting row lands in the balances
+// table with the expected amount and denom.
+func TestHarness_BankSendEndsUpInBalances(t *testing.T) {
+	h := newHarness(t)
+	defer h.Close()
+
+	require.NoError(t, h.runTx("tx", "bank", "send", "validator", "recipient", "1000stake", "--chain-id", testChainID, "--home", h.dir, "-y"))
+
+	require.Eventually(t, func() bool {
+		var amount string
+		err := h.db.Get(&amount, `SELECT amount FROM balances WHERE denom = 'stake' AND address = 'recipient'`)
+		return err == nil && amount == "1000"
+	}, 30*time.Second, 500*time.Millisecond)
+}
+
+// TestHarness_CrashMidBlockResumesFromCheckpoint kills the chain mid-block,
+// restarts it, and verifies the checkpoint subsystem replays cleanly without
+// double-processing or leaving a gap.
+func TestHarness_CrashMidBlockResumesFromCheckpoint(t *testing.T) {
+	h := newHarness(t)
+	defer h.Close()
+
+	require.NoError(t, h.runTx("tx", "staking", "delegate", "validator", "500stake", "--chain-id", testChainID, "--home", h.dir, "-y"))
+
+	heightBefore := h.processor.LastCommittedHeight()
+
+	h.restartGaiaDevChain()
+	h.processor = h.startProcessor()
+	h.startTraceWatcher()
+
+	require.Eventually(t, func() bool {
+		return h.processor.LastCommittedHeight() >= heightBefore
+	}, 30*time.Second, 500*time.Millisecond)
+
+	var count int
+	require.NoError(t, h.db.Get(&count, `SELECT count(*) FROM delegations WHERE delegator_address = 'validator'`))
+	require.Equal(t, 1, count, "the delegation row must not be duplicated by the crash/replay cycle")
+}
+
+// TestHarness_MalformedTraceLineDoesNotPanic injects malformed JSON directly
+// onto the FIFO and asserts it is surfaced as an error rather than crashing
+// the watcher: only truly unrecoverable I/O errors should panic.
+func TestHarness_MalformedTraceLineDoesNotPanic(t *testing.T) {
+	h := newHarness(t)
+	defer h.Close()
+
+	f, err := os.OpenFile(h.fifoPath, os.O_WRONLY, 0)
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, err = f.WriteString("{not valid json\n")
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		select {
+		case err := <-h.watcherErrCh:
+			return err != nil
+		default:
+			return false
+		}
+	}, 2*time.Second, 100*time.Millisecond, "the parse error must be surfaced on the watcher's error channel")
+
+	// If Watch had panicked instead of recovering from the parse error, the
+	// watcher goroutine would be dead and no further trace line would ever
+	// reach the processor. Prove it's still alive by driving a real
+	// transaction through and observing the height advance.
+	heightBefore := h.processor.LastCommittedHeight()
+
+	require.NoError(t, h.runTx("tx", "bank", "send", "validator", "recipient", "1stake", "--chain-id", testChainID, "--home", h.dir, "-y"))
+
+	require.Eventually(t, func() bool {
+		return h.processor.LastCommittedHeight() > heightBefore
+	}, 30*time.Second, 500*time.Millisecond)
+}
+
+func (h *harness) runTx(args ...string) error {
+	cmd := exec.CommandContext(context.Background(), gaiaBinary, args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("gaiad %v failed: %w: %s", args, err, out)
+	}
+
+	return nil
+}
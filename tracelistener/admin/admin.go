@@ -0,0 +1,155 @@
+// Package admin exposes a small JSON-RPC-style HTTP surface, in the spirit
+// of go-ethereum's admin namespace, for live inspection and control of a
+// running tracelistener process.
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+)
+
+// Status is a point-in-time snapshot of a processor's state, returned by the
+// tracelistener_status method.
+type Status struct {
+	ChainName          string   `json:"chain_name"`
+	LastHeight         uint64   `json:"last_height"`
+	ModuleNames        []string `json:"module_names"`
+	WritebackDepth     int      `json:"writeback_depth"`
+	CheckpointGapError string   `json:"checkpoint_gap_error,omitempty"`
+}
+
+// Target is implemented by a running processor (e.g. *gaia_processor.Processor)
+// to make it controllable over the admin endpoint.
+type Target interface {
+	Status() Status
+	EnableModule(name string) error
+	DisableModule(name string) error
+	ForceFlush() error
+	ReplayFrom(path string, fromHeight, toHeight uint64) error
+}
+
+// mutating lists every method name that requires a valid bearer token.
+var mutating = map[string]bool{
+	"tracelistener_modules_enable":  true,
+	"tracelistener_modules_disable": true,
+	"tracelistener_flush":           true,
+	"tracelistener_replay":          true,
+}
+
+type request struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type response struct {
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// Handler serves the admin JSON-RPC surface over HTTP. All requests are
+// POSTed to the handler's root as {"method": "...", "params": {...}}; methods
+// listed in mutating additionally require an "Authorization: Bearer <token>"
+// header matching the configured token.
+type Handler struct {
+	target Target
+	token  string
+}
+
+// New builds a Handler controlling target. A non-empty token is required to
+// invoke any mutating method.
+func New(target Target, token string) *Handler {
+	return &Handler{target: target, token: token}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, response{Error: err.Error()})
+		return
+	}
+
+	if mutating[req.Method] && !h.authorized(r) {
+		writeJSON(w, http.StatusUnauthorized, response{Error: "invalid or missing bearer token"})
+		return
+	}
+
+	result, err := h.dispatch(req)
+	if err != nil {
+		writeJSON(w, http.StatusOK, response{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response{Result: result})
+}
+
+func (h *Handler) authorized(r *http.Request) bool {
+	if h.token == "" {
+		return false
+	}
+
+	const prefix = "Bearer "
+
+	auth := r.Header.Get("Authorization")
+	if len(auth) <= len(prefix) || auth[:len(prefix)] != prefix {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(auth[len(prefix):]), []byte(h.token)) == 1
+}
+
+func (h *Handler) dispatch(req request) (interface{}, error) {
+	switch req.Method {
+	case "tracelistener_status":
+		return h.target.Status(), nil
+	case "tracelistener_modules":
+		return h.target.Status().ModuleNames, nil
+	case "tracelistener_modules_enable":
+		var p struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		return nil, h.target.EnableModule(p.Name)
+	case "tracelistener_modules_disable":
+		var p struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		return nil, h.target.DisableModule(p.Name)
+	case "tracelistener_flush":
+		return nil, h.target.ForceFlush()
+	case "tracelistener_replay":
+		var p struct {
+			Path       string `json:"path"`
+			FromHeight uint64 `json:"from_height"`
+			ToHeight   uint64 `json:"to_height"`
+		}
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		return nil, h.target.ReplayFrom(p.Path, p.FromHeight, p.ToHeight)
+	default:
+		return nil, errUnknownMethod(req.Method)
+	}
+}
+
+type errUnknownMethod string
+
+func (e errUnknownMethod) Error() string {
+	return "unknown method: " + string(e)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
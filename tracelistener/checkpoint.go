@@ -0,0 +1,93 @@
+package tracelistener
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/allinbits/tracelistener/tracelistener/tables"
+	"github.com/jmoiron/sqlx"
+)
+
+// CheckpointsTableName is the default tracelistener_checkpoints table name,
+// used both to create the table via DatabaseMigrations and to build the
+// default SQL Checkpointer.
+const CheckpointsTableName = "tracelistener_checkpoints"
+
+// Checkpoint represents the last durably committed processing state for a
+// single module on a single chain.
+type Checkpoint struct {
+	ChainName           string `db:"chain_name"`
+	ModuleName          string `db:"module_name"`
+	LastCommittedHeight uint64 `db:"last_committed_height"`
+}
+
+// Checkpointer persists and retrieves processing checkpoints so a
+// tracelistener instance can resume after a restart without leaving gaps in
+// the downstream tables.
+type Checkpointer interface {
+	// Persist writes cp transactionally. Callers are expected to call this
+	// alongside every writeback batch, and additionally on a timer even when
+	// the height hasn't changed, so long-running blocks still make forward
+	// progress.
+	Persist(cp Checkpoint) error
+
+	// LastCheckpoints returns the most recently persisted checkpoint for
+	// every module of chainName. found is false if no checkpoint exists yet.
+	LastCheckpoints(chainName string) (checkpoints []Checkpoint, found bool, err error)
+}
+
+// sqlExecer is the subset of *sqlx.DB used by checkpointSQL, narrowed so
+// tests can supply a fake without standing up a real database.
+type sqlExecer interface {
+	NamedExec(query string, arg interface{}) (sql.Result, error)
+	Select(dest interface{}, query string, args ...interface{}) error
+}
+
+// checkpointSQL is the Postgres/Cockroach-backed default Checkpointer
+// implementation, writing into the tracelistener_checkpoints table.
+type checkpointSQL struct {
+	db    sqlExecer
+	table tables.CheckpointsTable
+}
+
+// NewSQLCheckpointer builds a Checkpointer backed by db, using tableName as
+// the tracelistener_checkpoints table name.
+func NewSQLCheckpointer(db *sqlx.DB, tableName string) Checkpointer {
+	return &checkpointSQL{
+		db:    db,
+		table: tables.NewCheckpointsTable(tableName),
+	}
+}
+
+func (c *checkpointSQL) Persist(cp Checkpoint) error {
+	if _, err := c.db.NamedExec(c.table.Upsert(), cp); err != nil {
+		return fmt.Errorf("cannot persist checkpoint for module %s: %w", cp.ModuleName, err)
+	}
+
+	return nil
+}
+
+func (c *checkpointSQL) LastCheckpoints(chainName string) ([]Checkpoint, bool, error) {
+	var cps []Checkpoint
+
+	if err := c.db.Select(&cps, c.table.SelectByChain(), chainName); err != nil {
+		return nil, false, fmt.Errorf("cannot read checkpoints for chain %s: %w", chainName, err)
+	}
+
+	return cps, len(cps) > 0, nil
+}
+
+// ValidateCheckpointGap returns an error if firstTraceHeight leaves a gap
+// after lastCommittedHeight, i.e. blocks were skipped between the last
+// checkpoint and the first trace line tracelistener is about to process.
+func ValidateCheckpointGap(lastCommittedHeight, firstTraceHeight uint64) error {
+	if firstTraceHeight <= lastCommittedHeight+1 {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"gap detected: checkpoint is at height %d but first trace is at height %d",
+		lastCommittedHeight,
+		firstTraceHeight,
+	)
+}
@@ -0,0 +1,81 @@
+package tracelistener
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeExecer struct {
+	persisted []Checkpoint
+}
+
+func (f *fakeExecer) NamedExec(query string, arg interface{}) (sql.Result, error) {
+	cp := arg.(Checkpoint)
+
+	for i, existing := range f.persisted {
+		if existing.ChainName == cp.ChainName && existing.ModuleName == cp.ModuleName {
+			f.persisted[i] = cp
+			return nil, nil
+		}
+	}
+
+	f.persisted = append(f.persisted, cp)
+	return nil, nil
+}
+
+func (f *fakeExecer) Select(dest interface{}, query string, args ...interface{}) error {
+	out := dest.(*[]Checkpoint)
+	*out = f.persisted
+	return nil
+}
+
+func TestValidateCheckpointGap(t *testing.T) {
+	tests := []struct {
+		name                string
+		lastCommittedHeight uint64
+		firstTraceHeight    uint64
+		wantErr             bool
+	}{
+		{"contiguous height is fine", 10, 11, false},
+		{"same height is fine (idempotent replay)", 10, 10, false},
+		{"a gap of one block is detected", 10, 12, true},
+		{"a larger gap is detected", 10, 100, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCheckpointGap(tt.lastCommittedHeight, tt.firstTraceHeight)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCheckpointSQL_PersistAndReplay(t *testing.T) {
+	fe := &fakeExecer{}
+	c := &checkpointSQL{db: fe}
+
+	cp := Checkpoint{ChainName: "cosmoshub-4", ModuleName: "bank", LastCommittedHeight: 100}
+
+	require.NoError(t, c.Persist(cp))
+
+	cps, found, err := c.LastCheckpoints("cosmoshub-4")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, []Checkpoint{cp}, cps)
+
+	// Persisting the same height again (idempotent replay of the current
+	// block) must not create a second row nor error out.
+	require.NoError(t, c.Persist(cp))
+
+	cps, found, err = c.LastCheckpoints("cosmoshub-4")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Len(t, cps, 1)
+}
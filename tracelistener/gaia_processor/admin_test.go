@@ -0,0 +1,154 @@
+package gaia_processor
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/allinbits/tracelistener/tracelistener"
+	"github.com/allinbits/tracelistener/tracelistener/admin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type ownsAllModule struct {
+	name      string
+	processed int32
+}
+
+func (m *ownsAllModule) FlushCache() []tracelistener.WritebackOp { return nil }
+
+func (m *ownsAllModule) OwnsKey(key []byte) bool { return true }
+
+func (m *ownsAllModule) Process(data tracelistener.TraceOperation) error {
+	atomic.AddInt32(&m.processed, 1)
+	return nil
+}
+
+func (m *ownsAllModule) ModuleName() string { return m.name }
+
+func (m *ownsAllModule) TableSchema() string { return "" }
+
+// TestAdminHandler_EnableModule_ObservedByLifecycle proves that AddModule
+// (the primitive tracelistener_modules_enable drives, since processorByName
+// only constructs real named modules the admin endpoint can't introspect)
+// doesn't just update ModuleNames but is actually consumed by lifecycle on
+// the next trace, by asserting the newly-added module's own Process count.
+func TestAdminHandler_EnableModule_ObservedByLifecycle(t *testing.T) {
+	l, _ := zap.NewDevelopment()
+
+	m := &ownsAllModule{name: "bank"}
+
+	p := &Processor{
+		l:             l.Sugar(),
+		chainName:     "cosmoshub-4",
+		writeChan:     make(chan tracelistener.TraceOperation),
+		writebackChan: make(chan []tracelistener.WritebackOp, 1),
+	}
+
+	go p.lifecycle()
+
+	require.NoError(t, p.AddModule(m))
+	require.Contains(t, p.ModuleNames(), "bank")
+
+	p.writeChan <- tracelistener.TraceOperation{Key: []byte("any-key")}
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&m.processed) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestAdminHandler_RequiresTokenForMutatingMethods(t *testing.T) {
+	l, _ := zap.NewDevelopment()
+
+	p := &Processor{
+		l:             l.Sugar(),
+		chainName:     "cosmoshub-4",
+		writeChan:     make(chan tracelistener.TraceOperation),
+		writebackChan: make(chan []tracelistener.WritebackOp, 1),
+	}
+
+	go p.lifecycle()
+
+	srv := httptest.NewServer(admin.New(p, "s3cr3t"))
+	defer srv.Close()
+
+	body := bytes.NewBufferString(`{"method":"tracelistener_modules_enable","params":{"name":"auth"}}`)
+	resp, err := http.Post(srv.URL, "application/json", body)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.NotContains(t, p.ModuleNames(), "auth")
+}
+
+// TestAdminHandler_EnableDisableModule_RoundTrip drives tracelistener_modules_
+// enable/disable through a real HTTP round trip against admin.Handler,
+// proving the dispatch->EnableModule/DisableModule wiring works end to end
+// rather than only the direct AddModule call TestAdminHandler_
+// EnableModule_ObservedByLifecycle exercises.
+func TestAdminHandler_EnableDisableModule_RoundTrip(t *testing.T) {
+	l, _ := zap.NewDevelopment()
+
+	p := &Processor{
+		l:                l.Sugar(),
+		chainName:        "cosmoshub-4",
+		moduleProcessors: []Module{&ownsAllModule{name: "bank"}},
+		writeChan:        make(chan tracelistener.TraceOperation),
+		writebackChan:    make(chan []tracelistener.WritebackOp, 1),
+	}
+
+	go p.lifecycle()
+
+	srv := httptest.NewServer(admin.New(p, "s3cr3t"))
+	defer srv.Close()
+
+	post := func(payload string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, srv.URL, bytes.NewBufferString(payload))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer s3cr3t")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+
+		return resp
+	}
+
+	require.Contains(t, p.ModuleNames(), "bank")
+	require.NotContains(t, p.ModuleNames(), "auth")
+
+	resp := post(`{"method":"tracelistener_modules_enable","params":{"name":"auth"}}`)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, p.ModuleNames(), "auth")
+
+	resp = post(`{"method":"tracelistener_modules_disable","params":{"name":"bank"}}`)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NotContains(t, p.ModuleNames(), "bank")
+}
+
+func TestAdminHandler_Status_DoesNotRequireToken(t *testing.T) {
+	l, _ := zap.NewDevelopment()
+
+	p := &Processor{
+		l:                l.Sugar(),
+		chainName:        "cosmoshub-4",
+		moduleProcessors: []Module{&ownsAllModule{name: "bank"}},
+		writeChan:        make(chan tracelistener.TraceOperation),
+		writebackChan:    make(chan []tracelistener.WritebackOp, 1),
+	}
+
+	srv := httptest.NewServer(admin.New(p, "s3cr3t"))
+	defer srv.Close()
+
+	body := bytes.NewBufferString(`{"method":"tracelistener_status"}`)
+	resp, err := http.Post(srv.URL, "application/json", body)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
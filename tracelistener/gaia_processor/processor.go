@@ -2,16 +2,39 @@ package gaia_processor
 
 import (
 	"fmt"
+	"net/http"
+	"sync"
+	"time"
 
 	"github.com/allinbits/demeris-backend/models"
 
-	"github.com/allinbits/demeris-backend/tracelistener"
 	"github.com/allinbits/demeris-backend/tracelistener/config"
+	"github.com/allinbits/tracelistener/tracelistener"
+	"github.com/allinbits/tracelistener/tracelistener/admin"
+	"github.com/allinbits/tracelistener/tracelistener/tables"
 	"github.com/cosmos/cosmos-sdk/codec"
 	gaia "github.com/cosmos/gaia/v4/app"
 	"go.uber.org/zap"
 )
 
+// parallelFlushThreshold is the minimum amount of registered module processors
+// before lifecycle starts fanning FlushCache/Process calls out into goroutines.
+// Below this, the scheduling overhead isn't worth it for cheap chains.
+const parallelFlushThreshold = 3
+
+// defaultCheckpointEvery and defaultCheckpointInterval bound how often an
+// intermediate checkpoint is persisted even without a block height change, so
+// long-running blocks still make forward progress if the process dies
+// mid-block.
+const (
+	defaultCheckpointEvery    = 1000
+	defaultCheckpointInterval = 30 * time.Second
+)
+
+// flushDeliveryTimeout bounds how long ForceFlush waits for something to
+// drain writebackChan before giving up.
+const flushDeliveryTimeout = 5 * time.Second
+
 type Module interface {
 	FlushCache() []tracelistener.WritebackOp
 	OwnsKey(key []byte) bool
@@ -20,18 +43,59 @@ type Module interface {
 	TableSchema() string
 }
 
-// TODO: this singleton MUST go away.
-var p Processor
-
 type Processor struct {
-	l                *zap.SugaredLogger
-	writeChan        chan tracelistener.TraceOperation
-	writebackChan    chan []tracelistener.WritebackOp
-	cdc              codec.Marshaler
-	migrations       []string
-	lastHeight       uint64
-	chainName        string
-	moduleProcessors []Module
+	l                  *zap.SugaredLogger
+	writeChan          chan tracelistener.TraceOperation
+	writebackChan      chan []tracelistener.WritebackOp
+	cdc                codec.Marshaler
+	migrations         []string
+	chainName          string
+	moduleMu           sync.RWMutex
+	moduleProcessors   []Module
+	checkpointer       tracelistener.Checkpointer
+	checkpointEvery    int
+	checkpointInterval time.Duration
+	sinks              []tracelistener.WritebackSink
+
+	// heightMu guards lastHeight, traceCount and checkGapOnNextTrace: lifecycle
+	// is their sole writer, but LastCommittedHeight and Status read them from
+	// whatever goroutine calls them (the admin endpoint, the integration
+	// harness), concurrently with lifecycle running.
+	heightMu            sync.RWMutex
+	lastHeight          uint64
+	traceCount          int
+	checkGapOnNextTrace bool
+	checkpointGapErr    error
+
+	// pendingSinkWB holds writeback batches that were already committed to the
+	// SQL store via writebackChan but whose sink delivery failed, so the next
+	// successful delivery can retry them instead of losing them the moment
+	// flushModules drains the (now empty) module caches again. Only lifecycle
+	// touches it, so it needs no lock of its own.
+	pendingSinkWB []tracelistener.WritebackOp
+}
+
+// modules returns a snapshot of the currently registered module processors,
+// safe to range over while AddModule/RemoveModule run concurrently from the
+// admin endpoint.
+func (p *Processor) modules() []Module {
+	p.moduleMu.RLock()
+	defer p.moduleMu.RUnlock()
+
+	mp := make([]Module, len(p.moduleProcessors))
+	copy(mp, p.moduleProcessors)
+
+	return mp
+}
+
+// LastCommittedHeight returns the height of the last block whose writeback
+// batch was durably checkpointed. TraceWatcher uses this on startup to skip
+// trace lines that were already committed before a restart.
+func (p *Processor) LastCommittedHeight() uint64 {
+	p.heightMu.RLock()
+	defer p.heightMu.RUnlock()
+
+	return p.lastHeight
 }
 
 func (p *Processor) OpsChan() chan tracelistener.TraceOperation {
@@ -72,13 +136,37 @@ func New(logger *zap.SugaredLogger, cfg *config.Config) (tracelistener.DataProce
 
 	logger.Infow("gaia Processor initialized", "processors", c.ProcessorsEnabled)
 
-	p = Processor{
-		chainName:        cfg.ChainName,
-		l:                logger,
-		writeChan:        make(chan tracelistener.TraceOperation),
-		writebackChan:    make(chan []tracelistener.WritebackOp),
-		moduleProcessors: mp,
-		migrations:       tableSchemas,
+	if cfg.Checkpointer != nil {
+		tableSchemas = append(tableSchemas, tables.NewCheckpointsTable(tracelistener.CheckpointsTableName).CreateTable())
+	}
+
+	checkpointEvery := cfg.CheckpointEvery
+	if checkpointEvery == 0 {
+		checkpointEvery = defaultCheckpointEvery
+	}
+
+	checkpointInterval := cfg.CheckpointInterval
+	if checkpointInterval == 0 {
+		checkpointInterval = defaultCheckpointInterval
+	}
+
+	p := &Processor{
+		chainName:          cfg.ChainName,
+		l:                  logger,
+		writeChan:          make(chan tracelistener.TraceOperation),
+		writebackChan:      make(chan []tracelistener.WritebackOp),
+		moduleProcessors:   mp,
+		migrations:         tableSchemas,
+		checkpointer:       cfg.Checkpointer,
+		checkpointEvery:    checkpointEvery,
+		checkpointInterval: checkpointInterval,
+		sinks:              cfg.Sinks,
+	}
+
+	if p.checkpointer != nil {
+		if err := p.resumeFromCheckpoint(cfg.RewindToHeight); err != nil {
+			return nil, err
+		}
 	}
 
 	cdc, _ := gaia.MakeCodecs()
@@ -86,10 +174,55 @@ func New(logger *zap.SugaredLogger, cfg *config.Config) (tracelistener.DataProce
 
 	go p.lifecycle()
 
-	return &p, nil
+	if cfg.AdminAddr != "" {
+		go func() {
+			if err := http.ListenAndServe(cfg.AdminAddr, admin.New(p, cfg.AdminToken)); err != nil {
+				logger.Errorw("admin endpoint stopped", "error", err)
+			}
+		}()
+	}
+
+	return p, nil
+}
+
+// resumeFromCheckpoint reads the last persisted checkpoint for p.chainName
+// and fast-forwards lastHeight to it, so TraceWatcher can skip trace lines
+// that were already committed before a restart. If rewindTo is non-nil and
+// lower than the persisted height, it is used instead, to support
+// force-rewinding for reindexing.
+func (p *Processor) resumeFromCheckpoint(rewindTo *uint64) error {
+	cps, found, err := p.checkpointer.LastCheckpoints(p.chainName)
+	if err != nil {
+		return fmt.Errorf("cannot read checkpoint for chain %s: %w", p.chainName, err)
+	}
+
+	if !found {
+		return nil
+	}
+
+	var height uint64
+	for _, cp := range cps {
+		if cp.LastCommittedHeight > height {
+			height = cp.LastCommittedHeight
+		}
+	}
+
+	if rewindTo != nil && *rewindTo < height {
+		p.l.Infow("forcing rewind to earlier checkpoint for reindexing", "height", *rewindTo)
+		height = *rewindTo
+	}
+
+	p.l.Infow("resuming from checkpoint", "height", height)
+	p.lastHeight = height
+	p.checkGapOnNextTrace = true
+
+	return nil
 }
 
 func (p *Processor) AddModule(m Module) error {
+	p.moduleMu.Lock()
+	defer p.moduleMu.Unlock()
+
 	mn := m.ModuleName()
 	for _, em := range p.moduleProcessors {
 		if em.ModuleName() == mn {
@@ -102,6 +235,132 @@ func (p *Processor) AddModule(m Module) error {
 	return nil
 }
 
+// RemoveModule unregisters the module named mn, symmetric to AddModule. It
+// returns an error if no such module is currently registered.
+func (p *Processor) RemoveModule(mn string) error {
+	p.moduleMu.Lock()
+	defer p.moduleMu.Unlock()
+
+	for i, em := range p.moduleProcessors {
+		if em.ModuleName() == mn {
+			p.moduleProcessors = append(p.moduleProcessors[:i], p.moduleProcessors[i+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no such module %s", mn)
+}
+
+// ModuleNames returns the names of every currently registered module
+// processor.
+func (p *Processor) ModuleNames() []string {
+	mp := p.modules()
+
+	names := make([]string, len(mp))
+	for i, m := range mp {
+		names[i] = m.ModuleName()
+	}
+
+	return names
+}
+
+// EnableModule builds and registers the named module processor at runtime,
+// the same way New does at startup.
+func (p *Processor) EnableModule(name string) error {
+	m, err := processorByName(name, p.l)
+	if err != nil {
+		return err
+	}
+
+	return p.AddModule(m)
+}
+
+// DisableModule unregisters the named module processor at runtime.
+func (p *Processor) DisableModule(name string) error {
+	return p.RemoveModule(name)
+}
+
+// Status reports the processor's live state for the admin endpoint.
+func (p *Processor) Status() admin.Status {
+	p.heightMu.RLock()
+	lastHeight := p.lastHeight
+	var gapErr string
+	if p.checkpointGapErr != nil {
+		gapErr = p.checkpointGapErr.Error()
+	}
+	p.heightMu.RUnlock()
+
+	return admin.Status{
+		ChainName:          p.chainName,
+		LastHeight:         lastHeight,
+		ModuleNames:        p.ModuleNames(),
+		WritebackDepth:     len(p.writebackChan),
+		CheckpointGapError: gapErr,
+	}
+}
+
+// ForceFlush runs FlushCache on every module and delivers the result on
+// writebackChan and every configured sink without waiting for a block
+// boundary, so callers (e.g. a graceful shutdown path) can drain module
+// caches on demand while keeping the SQL store and any streaming sinks in
+// lockstep. It gives up and returns an error if nothing drains writebackChan
+// within flushDeliveryTimeout.
+func (p *Processor) ForceFlush() error {
+	wb := p.flushModules()
+
+	select {
+	case p.writebackChan <- wb:
+	case <-time.After(flushDeliveryTimeout):
+		return fmt.Errorf("force flush: no writeback consumer within %s", flushDeliveryTimeout)
+	}
+
+	return p.deliverToSinks(wb)
+}
+
+// ReplayFrom drives a tracelistener.TraceWatcher over the trace file at path
+// and re-feeds recorded operations back through the processor's regular
+// writeChan, the same as live trace data. Operations below fromHeight are
+// skipped; it returns right after feeding the first operation whose height is
+// at or above toHeight, so later operations of that block are not replayed.
+// toHeight must be non-zero. If the file holds no operation at or above
+// toHeight, ReplayFrom keeps waiting for one.
+func (p *Processor) ReplayFrom(path string, fromHeight, toHeight uint64) error {
+	if toHeight == 0 {
+		return fmt.Errorf("replay requires a non-zero toHeight to bound the range")
+	}
+
+	dataChan := make(chan tracelistener.TraceOperation)
+	errChan := make(chan error, 1)
+
+	tw := tracelistener.TraceWatcher{
+		DataSourcePath: path,
+		DataChan:       dataChan,
+		ErrorChan:      errChan,
+		Logger:         p.l,
+	}
+
+	go tw.Watch()
+
+	for {
+		select {
+		case data := <-dataChan:
+			if data.BlockHeight < fromHeight {
+				continue
+			}
+
+			p.writeChan <- data
+
+			if data.BlockHeight >= toHeight {
+				return nil
+			}
+		case err := <-errChan:
+			if err != nil {
+				return fmt.Errorf("replay from %s: %w", path, err)
+			}
+		}
+	}
+}
+
 func processorByName(name string, logger *zap.SugaredLogger) (Module, error) {
 	switch name {
 	default:
@@ -136,43 +395,243 @@ func processorByName(name string, logger *zap.SugaredLogger) (Module, error) {
 }
 
 func (p *Processor) lifecycle() {
-	for data := range p.writeChan {
-		if data.BlockHeight != p.lastHeight && data.BlockHeight != 0 {
-			wb := make([]tracelistener.WritebackOp, 0, len(p.moduleProcessors))
-
-			for _, mp := range p.moduleProcessors {
-				cd := mp.FlushCache()
-				for _, entry := range cd {
-					if entry.Data == nil {
-						continue
-					}
-
-					for i := 0; i < len(entry.Data); i++ {
-						entry.Data[i] = entry.Data[i].WithChainName(p.chainName)
-					}
-					wb = append(wb, entry)
-				}
+	var tickerC <-chan time.Time
+	if p.checkpointer != nil {
+		ticker := time.NewTicker(p.checkpointInterval)
+		defer ticker.Stop()
+		tickerC = ticker.C
+	}
+
+	for {
+		select {
+		case data, ok := <-p.writeChan:
+			if !ok {
+				return
 			}
 
-			p.writebackChan <- wb
+			p.handleTrace(data)
+		case <-tickerC:
+			// Periodic checkpoint so long-running blocks still make forward
+			// progress even without a height change.
+			p.persistCheckpoint(nil)
+		}
+	}
+}
 
-			p.l.Infow("processed new block", "height", p.lastHeight)
+func (p *Processor) handleTrace(data tracelistener.TraceOperation) {
+	p.heightMu.RLock()
+	lastHeight := p.lastHeight
+	p.heightMu.RUnlock()
 
+	if data.BlockHeight != 0 && data.BlockHeight <= lastHeight {
+		// Already committed past this height by a previous run; skip it so a
+		// resume after a crash doesn't double-process.
+		return
+	}
+
+	p.heightMu.Lock()
+	if p.checkGapOnNextTrace && data.BlockHeight != 0 {
+		p.checkGapOnNextTrace = false
+
+		if err := tracelistener.ValidateCheckpointGap(p.lastHeight, data.BlockHeight); err != nil {
+			// Surfaced through Status() (tracelistener_status) rather than
+			// merely logged, so an operator or caller has a durable, queryable
+			// signal that blocks were skipped across this restart, not just a
+			// log line to grep for.
+			p.checkpointGapErr = err
+			p.l.Errorw("gap detected resuming from checkpoint", "error", err)
+		}
+	}
+	p.heightMu.Unlock()
+
+	if data.BlockHeight != lastHeight && data.BlockHeight != 0 {
+		wb := p.flushModules()
+
+		p.writebackChan <- wb
+
+		// Prepend any batch a previous sink delivery failed on, so a failure
+		// never silently drops data once flushModules has already drained the
+		// module caches that produced it.
+		pending := append(p.pendingSinkWB, wb...)
+
+		if err := p.deliverToSinks(pending); err != nil {
+			// A row is only considered committed once every configured sink
+			// has acked it, so don't advance the checkpoint on a failed
+			// delivery even though the SQL store (via writebackChan) already
+			// has the batch. Keep it buffered for the next block's retry.
+			p.pendingSinkWB = pending
+			p.l.Errorw("cannot deliver writeback batch to a sink, buffering for retry", "error", err, "height", data.BlockHeight)
+		} else {
+			p.pendingSinkWB = nil
+			p.l.Infow("processed new block", "height", lastHeight)
+
+			p.heightMu.Lock()
 			p.lastHeight = data.BlockHeight
+			p.traceCount = 0
+			p.heightMu.Unlock()
+
+			p.persistCheckpoint(wb)
 		}
+	}
 
-		for _, mp := range p.moduleProcessors {
-			if !mp.OwnsKey(data.Key) {
-				continue
-			}
+	p.processModules(data)
 
-			if err := mp.Process(data); err != nil {
-				p.l.Errorw(
-					"error while processing data",
-					"error", err,
-					"data", data,
-					"moduleName", mp.ModuleName())
-			}
+	p.heightMu.Lock()
+	p.traceCount++
+	flushCheckpoint := p.checkpointEvery > 0 && p.traceCount >= p.checkpointEvery
+	if flushCheckpoint {
+		p.traceCount = 0
+	}
+	p.heightMu.Unlock()
+
+	if flushCheckpoint {
+		p.persistCheckpoint(nil)
+	}
+}
+
+// persistCheckpoint writes the current lastHeight for every module
+// processor. It is a no-op if no Checkpointer was configured. wb is accepted
+// to mirror the call sites driving it (a writeback batch on a block
+// transition, or nil on the periodic timer) but isn't otherwise used here.
+func (p *Processor) persistCheckpoint(wb []tracelistener.WritebackOp) {
+	if p.checkpointer == nil {
+		return
+	}
+
+	p.heightMu.RLock()
+	lastHeight := p.lastHeight
+	p.heightMu.RUnlock()
+
+	for _, mp := range p.modules() {
+		cp := tracelistener.Checkpoint{
+			ChainName:           p.chainName,
+			ModuleName:          mp.ModuleName(),
+			LastCommittedHeight: lastHeight,
 		}
+
+		if err := p.checkpointer.Persist(cp); err != nil {
+			p.l.Errorw("cannot persist checkpoint", "error", err, "moduleName", mp.ModuleName())
+		}
+	}
+}
+
+// flushModules runs FlushCache on every registered module processor, fanning
+// out into bounded goroutines once len(moduleProcessors) crosses
+// parallelFlushThreshold so cheap chains with few modules don't pay any
+// scheduling overhead. The aggregate wall time is bounded by the slowest
+// module rather than the sum of all of them.
+func (p *Processor) flushModules() []tracelistener.WritebackOp {
+	mods := p.modules()
+
+	if len(mods) < parallelFlushThreshold {
+		wb := make([]tracelistener.WritebackOp, 0, len(mods))
+		for _, mp := range mods {
+			wb = append(wb, p.collectFlush(mp)...)
+		}
+
+		return wb
+	}
+
+	results := make([][]tracelistener.WritebackOp, len(mods))
+
+	var wg sync.WaitGroup
+	wg.Add(len(mods))
+
+	for i, mp := range mods {
+		i, mp := i, mp
+
+		go func() {
+			defer wg.Done()
+			results[i] = p.collectFlush(mp)
+		}()
+	}
+
+	wg.Wait()
+
+	wb := make([]tracelistener.WritebackOp, 0, len(mods))
+	for _, r := range results {
+		wb = append(wb, r...)
+	}
+
+	return wb
+}
+
+// collectFlush flushes a single module's cache, stamps the chain name onto
+// every resulting row, and fills in a stream topic for sinks that need one.
+func (p *Processor) collectFlush(mp Module) []tracelistener.WritebackOp {
+	var wb []tracelistener.WritebackOp
+
+	for _, entry := range mp.FlushCache() {
+		if entry.Data == nil {
+			continue
+		}
+
+		for i := 0; i < len(entry.Data); i++ {
+			entry.Data[i] = entry.Data[i].WithChainName(p.chainName)
+		}
+
+		if entry.Topic == "" {
+			entry.Topic = fmt.Sprintf("%s.%s", p.chainName, mp.ModuleName())
+		}
+
+		wb = append(wb, entry)
+	}
+
+	return wb
+}
+
+// deliverToSinks writes wb to every configured WritebackSink (e.g. a
+// streaming Kafka/NATS-JetStream sink), in addition to the SQL store reached
+// through writebackChan.
+func (p *Processor) deliverToSinks(wb []tracelistener.WritebackOp) error {
+	for _, sink := range p.sinks {
+		if err := sink.Write(wb); err != nil {
+			return fmt.Errorf("sink %s: %w", sink.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// processModules hands data to every module that owns its key. Modules own
+// disjoint keyspaces, so they can run concurrently once there are enough of
+// them to make fanning out worthwhile.
+func (p *Processor) processModules(data tracelistener.TraceOperation) {
+	mods := p.modules()
+
+	if len(mods) < parallelFlushThreshold {
+		for _, mp := range mods {
+			p.processModule(mp, data)
+		}
+
+		return
+	}
+
+	var wg sync.WaitGroup
+
+	for _, mp := range mods {
+		mp := mp
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.processModule(mp, data)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (p *Processor) processModule(mp Module, data tracelistener.TraceOperation) {
+	if !mp.OwnsKey(data.Key) {
+		return
+	}
+
+	if err := mp.Process(data); err != nil {
+		p.l.Errorw(
+			"error while processing data",
+			"error", err,
+			"data", data,
+			"moduleName", mp.ModuleName())
 	}
 }
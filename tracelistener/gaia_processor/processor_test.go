@@ -0,0 +1,89 @@
+package gaia_processor
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/allinbits/tracelistener/tracelistener"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type blockingFlushModule struct {
+	name      string
+	flushTime time.Duration
+	flushed   int32
+}
+
+func (m *blockingFlushModule) FlushCache() []tracelistener.WritebackOp {
+	time.Sleep(m.flushTime)
+	atomic.AddInt32(&m.flushed, 1)
+	return nil
+}
+
+func (m *blockingFlushModule) OwnsKey(key []byte) bool { return false }
+
+func (m *blockingFlushModule) Process(data tracelistener.TraceOperation) error { return nil }
+
+func (m *blockingFlushModule) ModuleName() string { return m.name }
+
+func (m *blockingFlushModule) TableSchema() string { return "" }
+
+func TestProcessor_flushModules_runsConcurrentlyAboveThreshold(t *testing.T) {
+	l, _ := zap.NewDevelopment()
+
+	modules := make([]Module, parallelFlushThreshold+1)
+	for i := range modules {
+		modules[i] = &blockingFlushModule{name: string(rune('a' + i)), flushTime: 100 * time.Millisecond}
+	}
+
+	p := &Processor{
+		l:                l.Sugar(),
+		moduleProcessors: modules,
+	}
+
+	start := time.Now()
+	p.flushModules()
+	elapsed := time.Since(start)
+
+	// Wall time should be bounded by the slowest single flush, not the sum of
+	// all of them serially.
+	require.Less(t, elapsed, time.Duration(len(modules))*100*time.Millisecond)
+}
+
+func TestProcessor_handleTrace_surfacesCheckpointGapViaStatus(t *testing.T) {
+	l, _ := zap.NewDevelopment()
+
+	p := &Processor{
+		l:             l.Sugar(),
+		chainName:     "cosmoshub-4",
+		writebackChan: make(chan []tracelistener.WritebackOp, 1),
+	}
+	p.lastHeight = 10
+	p.checkGapOnNextTrace = true
+
+	p.handleTrace(tracelistener.TraceOperation{BlockHeight: 15})
+
+	require.Contains(t, p.Status().CheckpointGapError, "gap detected")
+}
+
+func TestProcessor_flushModules_belowThresholdRunsSerially(t *testing.T) {
+	l, _ := zap.NewDevelopment()
+
+	modules := make([]Module, parallelFlushThreshold-1)
+	for i := range modules {
+		modules[i] = &blockingFlushModule{name: string(rune('a' + i)), flushTime: 10 * time.Millisecond}
+	}
+
+	p := &Processor{
+		l:                l.Sugar(),
+		moduleProcessors: modules,
+	}
+
+	start := time.Now()
+	p.flushModules()
+	elapsed := time.Since(start)
+
+	require.GreaterOrEqual(t, elapsed, time.Duration(len(modules))*10*time.Millisecond)
+}
@@ -0,0 +1,62 @@
+package gaia_processor
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/allinbits/tracelistener/tracelistener"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestProcessor_ReplayFrom_RequiresNonZeroToHeight(t *testing.T) {
+	p := &Processor{}
+
+	require.Error(t, p.ReplayFrom("irrelevant", 0, 0))
+}
+
+// TestProcessor_ReplayFrom_BoundsByHeight proves ReplayFrom skips trace lines
+// below fromHeight, re-feeds the rest onto writeChan, and returns as soon as
+// toHeight is reached rather than tailing the file indefinitely.
+func TestProcessor_ReplayFrom_BoundsByHeight(t *testing.T) {
+	l, _ := zap.NewDevelopment()
+
+	f, err := os.CreateTemp("", "replay_test")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	p := &Processor{
+		l:         l.Sugar(),
+		writeChan: make(chan tracelistener.TraceOperation, 3),
+	}
+
+	replayErr := make(chan error, 1)
+	go func() {
+		replayErr <- p.ReplayFrom(f.Name(), 2, 3)
+	}()
+
+	lines := []string{
+		`{"operation":"write","key":"a2V5LTE=","value":"dmFsLTE=","block_height":1,"metadata":null}`,
+		`{"operation":"write","key":"a2V5LTI=","value":"dmFsLTI=","block_height":2,"metadata":null}`,
+		`{"operation":"write","key":"a2V5LTM=","value":"dmFsLTM=","block_height":3,"metadata":null}`,
+	}
+	for _, line := range lines {
+		_, err := f.WriteString(line)
+		require.NoError(t, err)
+	}
+
+	var heights []uint64
+	require.Eventually(t, func() bool {
+		select {
+		case data := <-p.writeChan:
+			heights = append(heights, data.BlockHeight)
+		default:
+		}
+
+		return len(heights) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, <-replayErr)
+	require.Equal(t, []uint64{2, 3}, heights)
+}
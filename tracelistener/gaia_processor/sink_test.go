@@ -0,0 +1,205 @@
+package gaia_processor
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	models "github.com/allinbits/demeris-backend-models/tracelistener"
+	"github.com/allinbits/tracelistener/tracelistener"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type flushingModule struct {
+	name string
+	data []tracelistener.WritebackOp
+}
+
+func (m *flushingModule) FlushCache() []tracelistener.WritebackOp {
+	d := m.data
+	m.data = nil
+	return d
+}
+
+func (m *flushingModule) OwnsKey(key []byte) bool { return false }
+
+func (m *flushingModule) Process(data tracelistener.TraceOperation) error { return nil }
+
+func (m *flushingModule) ModuleName() string { return m.name }
+
+func (m *flushingModule) TableSchema() string { return "" }
+
+type fakeSink struct {
+	name     string
+	received [][]tracelistener.WritebackOp
+	fail     bool
+}
+
+func (s *fakeSink) Name() string { return s.name }
+
+func (s *fakeSink) Write(ops []tracelistener.WritebackOp) error {
+	if s.fail {
+		return fmt.Errorf("sink unavailable")
+	}
+
+	s.received = append(s.received, ops)
+
+	return nil
+}
+
+func TestProcessor_deliverToSinks_fansOutAndStampsTopic(t *testing.T) {
+	l, _ := zap.NewDevelopment()
+
+	sink := &fakeSink{name: "jetstream"}
+
+	p := &Processor{
+		l:         l.Sugar(),
+		chainName: "cosmoshub-4",
+		moduleProcessors: []Module{
+			&flushingModule{name: "bank", data: []tracelistener.WritebackOp{{DatabaseExec: "insert", Data: testRows()}}},
+		},
+		sinks: []tracelistener.WritebackSink{sink},
+	}
+
+	wb := p.flushModules()
+	require.Len(t, wb, 1)
+	require.Equal(t, "cosmoshub-4.bank", wb[0].Topic)
+
+	require.NoError(t, p.deliverToSinks(wb))
+	require.Len(t, sink.received, 1)
+}
+
+func TestProcessor_handleTrace_doesNotCheckpointOnSinkFailure(t *testing.T) {
+	l, _ := zap.NewDevelopment()
+
+	sink := &fakeSink{name: "jetstream", fail: true}
+
+	p := &Processor{
+		l:         l.Sugar(),
+		chainName: "cosmoshub-4",
+		moduleProcessors: []Module{
+			&flushingModule{name: "bank", data: []tracelistener.WritebackOp{{DatabaseExec: "insert"}}},
+		},
+		sinks:         []tracelistener.WritebackSink{sink},
+		writeChan:     make(chan tracelistener.TraceOperation),
+		writebackChan: make(chan []tracelistener.WritebackOp, 1),
+	}
+
+	go p.lifecycle()
+
+	go func() {
+		p.writeChan <- tracelistener.TraceOperation{BlockHeight: 2}
+	}()
+
+	select {
+	case <-p.writebackChan:
+	case <-time.After(time.Second):
+		t.Fatal("expected a writeback batch on writebackChan")
+	}
+
+	require.Eventually(t, func() bool {
+		return len(sink.received) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	require.Equal(t, uint64(0), p.LastCommittedHeight())
+}
+
+func TestProcessor_handleTrace_retriesBufferedBatchAfterSinkRecovers(t *testing.T) {
+	l, _ := zap.NewDevelopment()
+
+	sink := &fakeSink{name: "jetstream", fail: true}
+
+	moduleA := &flushingModule{name: "bank", data: []tracelistener.WritebackOp{{DatabaseExec: "insert-a", Data: testRows()}}}
+	moduleB := &flushingModule{name: "auth"}
+
+	p := &Processor{
+		l:                l.Sugar(),
+		chainName:        "cosmoshub-4",
+		moduleProcessors: []Module{moduleA, moduleB},
+		sinks:            []tracelistener.WritebackSink{sink},
+		writeChan:        make(chan tracelistener.TraceOperation),
+		writebackChan:    make(chan []tracelistener.WritebackOp, 2),
+	}
+
+	go p.lifecycle()
+
+	// Block 2 flushes moduleA's single op and fails to deliver it; the
+	// module's cache is now empty, so moduleB.FlushCache() on a later block
+	// must not be the only thing the sink ever gets.
+	p.writeChan <- tracelistener.TraceOperation{BlockHeight: 2}
+
+	select {
+	case <-p.writebackChan:
+	case <-time.After(time.Second):
+		t.Fatal("expected a writeback batch on writebackChan for block 2")
+	}
+
+	require.Eventually(t, func() bool {
+		return len(sink.received) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	sink.fail = false
+	moduleB.data = []tracelistener.WritebackOp{{DatabaseExec: "insert-b", Data: testRows()}}
+
+	p.writeChan <- tracelistener.TraceOperation{BlockHeight: 3}
+
+	select {
+	case <-p.writebackChan:
+	case <-time.After(time.Second):
+		t.Fatal("expected a writeback batch on writebackChan for block 3")
+	}
+
+	require.Eventually(t, func() bool {
+		return len(sink.received) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	require.ElementsMatch(t, []string{"insert-a", "insert-b"}, execsOf(sink.received[0]))
+	require.Equal(t, uint64(3), p.LastCommittedHeight())
+}
+
+// TestProcessor_ForceFlush_DeliversToWritebackChanAndSinks proves ForceFlush
+// drains every module's cache on demand (not just on a block boundary) and
+// delivers the result to both writebackChan and every configured sink, the
+// same as a normal block transition would.
+func TestProcessor_ForceFlush_DeliversToWritebackChanAndSinks(t *testing.T) {
+	l, _ := zap.NewDevelopment()
+
+	sink := &fakeSink{name: "jetstream"}
+
+	p := &Processor{
+		l:         l.Sugar(),
+		chainName: "cosmoshub-4",
+		moduleProcessors: []Module{
+			&flushingModule{name: "bank", data: []tracelistener.WritebackOp{{DatabaseExec: "insert", Data: testRows()}}},
+		},
+		sinks:         []tracelistener.WritebackSink{sink},
+		writebackChan: make(chan []tracelistener.WritebackOp, 1),
+	}
+
+	require.NoError(t, p.ForceFlush())
+
+	select {
+	case wb := <-p.writebackChan:
+		require.Len(t, wb, 1)
+	default:
+		t.Fatal("expected ForceFlush to deliver a writeback batch on writebackChan")
+	}
+
+	require.Len(t, sink.received, 1)
+}
+
+// testRows returns a single-row Data slice: collectFlush drops ops whose Data
+// is nil, so fixtures that must reach writebackChan and the sinks carry a row.
+func testRows() []models.DatabaseEntrier {
+	return []models.DatabaseEntrier{models.AuthRow{Address: "address"}}
+}
+
+func execsOf(ops []tracelistener.WritebackOp) []string {
+	execs := make([]string, len(ops))
+	for i, op := range ops {
+		execs[i] = op.DatabaseExec
+	}
+
+	return execs
+}
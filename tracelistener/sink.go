@@ -0,0 +1,93 @@
+package tracelistener
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// WritebackSink is anything a processor can deliver a writeback batch to. The
+// SQL sink keeps the existing Postgres/Cockroach behavior; the JetStream sink
+// lets downstream consumers subscribe to a change stream instead of polling
+// the database.
+type WritebackSink interface {
+	// Name identifies the sink in logs and errors.
+	Name() string
+
+	// Write delivers ops. It must not return until every row has been
+	// durably accepted by the sink's backend (e.g. a broker ack), since
+	// Processor only advances its checkpoint once every configured sink's
+	// Write call succeeds.
+	Write(ops []WritebackOp) error
+}
+
+// SQLSink is the default WritebackSink, writing each row through its
+// WritebackOp's named SQL statement.
+type SQLSink struct {
+	db *sqlx.DB
+}
+
+// NewSQLSink builds a SQLSink backed by db.
+func NewSQLSink(db *sqlx.DB) *SQLSink {
+	return &SQLSink{db: db}
+}
+
+func (s *SQLSink) Name() string { return "sql" }
+
+func (s *SQLSink) Write(ops []WritebackOp) error {
+	for _, op := range ops {
+		for _, row := range op.Data {
+			if _, err := s.db.NamedExec(op.DatabaseExec, row); err != nil {
+				return fmt.Errorf("sql sink: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// jetStreamPublisher is the subset of a NATS JetStream context used by
+// JetStreamSink, narrowed so tests can supply an in-memory fake broker.
+type jetStreamPublisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// JetStreamSink publishes each row in a writeback batch as JSON onto NATS
+// JetStream, on the subject named by the WritebackOp's Topic (for processor
+// batches, "<chain_name>.<module_name>"). Messages carry no per-row key; the
+// row's primary key is only present inside the JSON payload.
+// Publish blocks until JetStream acks the message, so a row is never
+// considered committed (and the checkpoint never advances) before the broker
+// has durably stored it.
+type JetStreamSink struct {
+	js jetStreamPublisher
+}
+
+// NewJetStreamSink builds a JetStreamSink publishing through js.
+func NewJetStreamSink(js jetStreamPublisher) *JetStreamSink {
+	return &JetStreamSink{js: js}
+}
+
+func (s *JetStreamSink) Name() string { return "jetstream" }
+
+func (s *JetStreamSink) Write(ops []WritebackOp) error {
+	for _, op := range ops {
+		if op.Topic == "" {
+			return fmt.Errorf("jetstream sink: writeback op has no topic")
+		}
+
+		for _, row := range op.Data {
+			payload, err := json.Marshal(row)
+			if err != nil {
+				return fmt.Errorf("jetstream sink: cannot marshal row: %w", err)
+			}
+
+			if err := s.js.Publish(op.Topic, payload); err != nil {
+				return fmt.Errorf("jetstream sink: publish to %s: %w", op.Topic, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package tracelistener
+
+import (
+	"fmt"
+	"testing"
+
+	models "github.com/allinbits/demeris-backend-models/tracelistener"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBroker struct {
+	published map[string][][]byte
+	failNext  bool
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{published: map[string][][]byte{}}
+}
+
+func (f *fakeBroker) Publish(subject string, data []byte) error {
+	if f.failNext {
+		f.failNext = false
+		return fmt.Errorf("broker unavailable")
+	}
+
+	f.published[subject] = append(f.published[subject], data)
+
+	return nil
+}
+
+func TestJetStreamSink_Write(t *testing.T) {
+	broker := newFakeBroker()
+	sink := NewJetStreamSink(broker)
+
+	ops := []WritebackOp{
+		{
+			Topic: "cosmoshub-4.auth",
+			Data: []models.DatabaseEntrier{
+				models.AuthRow{
+					TracelistenerDatabaseRow: models.TracelistenerDatabaseRow{ChainName: "cosmoshub-4"},
+					Address:                  "address",
+				},
+			},
+		},
+	}
+
+	require.NoError(t, sink.Write(ops))
+	require.Len(t, broker.published["cosmoshub-4.auth"], 1)
+}
+
+func TestJetStreamSink_Write_MissingTopicErrors(t *testing.T) {
+	broker := newFakeBroker()
+	sink := NewJetStreamSink(broker)
+
+	ops := []WritebackOp{
+		{
+			Data: []models.DatabaseEntrier{
+				models.AuthRow{Address: "address"},
+			},
+		},
+	}
+
+	require.Error(t, sink.Write(ops))
+}
+
+func TestJetStreamSink_Write_BrokerFailureIsNotCommitted(t *testing.T) {
+	broker := newFakeBroker()
+	broker.failNext = true
+	sink := NewJetStreamSink(broker)
+
+	ops := []WritebackOp{
+		{
+			Topic: "cosmoshub-4.auth",
+			Data: []models.DatabaseEntrier{
+				models.AuthRow{Address: "address"},
+			},
+		},
+	}
+
+	require.Error(t, sink.Write(ops))
+	require.Empty(t, broker.published["cosmoshub-4.auth"])
+}
@@ -0,0 +1,154 @@
+package tracelistener
+
+import (
+	"reflect"
+	"sync"
+
+	models "github.com/allinbits/demeris-backend-models/tracelistener"
+)
+
+// WritebackOp is a single, pending batch of rows destined for one database
+// table, alongside the statement used to write them. Topic identifies which
+// stream subject/topic a WritebackSink should publish Data to; it is ignored
+// by sinks that don't need it (e.g. the SQL sink).
+type WritebackOp struct {
+	DatabaseExec string
+	Topic        string
+	Data         []models.DatabaseEntrier
+}
+
+// InterfaceSlice returns Data as a plain []interface{}, the shape sqlx's
+// named-statement helpers expect.
+func (w WritebackOp) InterfaceSlice() []interface{} {
+	ret := make([]interface{}, len(w.Data))
+	for i, d := range w.Data {
+		ret[i] = d
+	}
+
+	return ret
+}
+
+// bufferPools holds one sync.Pool per capacity bucket, so Data slices of
+// similar size are reused instead of being reallocated on every
+// SplitStatements/Release cycle.
+var bufferPools sync.Map // map[int]*sync.Pool, keyed by bucket capacity
+
+// capacityBucket rounds n up to the next power-of-two-ish bucket, so a pool
+// serves a small, stable set of sizes instead of one per distinct length.
+func capacityBucket(n int) int {
+	bucket := 1
+	for bucket < n {
+		bucket *= 2
+	}
+
+	return bucket
+}
+
+func getBuffer(capacity int) []models.DatabaseEntrier {
+	bucket := capacityBucket(capacity)
+
+	poolI, _ := bufferPools.LoadOrStore(bucket, &sync.Pool{
+		New: func() interface{} {
+			buf := make([]models.DatabaseEntrier, 0, bucket)
+			return &buf
+		},
+	})
+
+	buf := poolI.(*sync.Pool).Get().(*[]models.DatabaseEntrier)
+	return (*buf)[:0]
+}
+
+func putBuffer(buf []models.DatabaseEntrier) {
+	if cap(buf) == 0 {
+		return
+	}
+
+	bucket := capacityBucket(cap(buf))
+
+	poolI, ok := bufferPools.Load(bucket)
+	if !ok {
+		return
+	}
+
+	buf = buf[:0]
+	poolI.(*sync.Pool).Put(&buf)
+}
+
+// Release returns every Data buffer held by wb to the shared pool. Callers
+// must only call this once the SQL exec for wb has completed, and must not
+// keep using wb or any buffer still referenced by a WritebackOp queued on
+// writebackChan afterwards.
+func (w WritebackOp) Release() {
+	putBuffer(w.Data)
+}
+
+var (
+	fieldsAmountCache   = map[reflect.Type]int{}
+	fieldsAmountCacheMu sync.RWMutex
+)
+
+// fieldsAmount returns the number of top-level struct fields of v's concrete
+// type, i.e. the number of bind parameters a single row of v consumes in a
+// named SQL statement. The reflection result is cached per type so repeated
+// calls for the same row type (the common case, since a batch is normally
+// homogeneous) don't pay reflection cost again.
+func fieldsAmount(v models.DatabaseEntrier) int {
+	t := reflect.TypeOf(v)
+
+	fieldsAmountCacheMu.RLock()
+	n, ok := fieldsAmountCache[t]
+	fieldsAmountCacheMu.RUnlock()
+	if ok {
+		return n
+	}
+
+	n = t.NumField()
+
+	fieldsAmountCacheMu.Lock()
+	fieldsAmountCache[t] = n
+	fieldsAmountCacheMu.Unlock()
+
+	return n
+}
+
+// SplitStatements breaks wb up so that no resulting WritebackOp needs more
+// than limit bind parameters. If the whole batch already fits, its Data is
+// copied into a pool-obtained buffer and returned as the sole result;
+// otherwise it degrades to one row per statement, and a single row whose
+// fields alone exceed limit is still returned as its own statement. Either
+// way, every returned WritebackOp owns a buffer obtained from the pool, so
+// callers can always call Release() on it. A wb with empty Data is returned
+// unchanged, without a pool buffer.
+func (w WritebackOp) SplitStatements(limit int) []WritebackOp {
+	if len(w.Data) == 0 {
+		return []WritebackOp{w}
+	}
+
+	fa := fieldsAmount(w.Data[0])
+
+	if fa*len(w.Data) <= limit {
+		buf := getBuffer(len(w.Data))
+		buf = append(buf, w.Data...)
+
+		return []WritebackOp{{
+			DatabaseExec: w.DatabaseExec,
+			Topic:        w.Topic,
+			Data:         buf,
+		}}
+	}
+
+	result := make([]WritebackOp, 0, len(w.Data))
+
+	for _, d := range w.Data {
+		buf := getBuffer(1)
+		buf = append(buf, d)
+
+		result = append(result, WritebackOp{
+			DatabaseExec: w.DatabaseExec,
+			Topic:        w.Topic,
+			Data:         buf,
+		})
+	}
+
+	return result
+}
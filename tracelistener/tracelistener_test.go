@@ -347,6 +347,52 @@ func TestWritebackOp_SplitStatements(t *testing.T) {
 			})
 
 			require.Len(t, val, int(tt.expectedAmount))
+
+			for _, op := range val {
+				op.Release()
+			}
 		})
 	}
 }
+
+func TestWritebackOp_SplitStatements_PreservesTopic(t *testing.T) {
+	wb := tracelistener.WritebackOp{
+		DatabaseExec: "statement",
+		Topic:        "cosmoshub-4.auth",
+		Data: []models.DatabaseEntrier{
+			models.AuthRow{Address: "address-1"},
+			models.AuthRow{Address: "address-2"},
+		},
+	}
+
+	// limit forces the degrade-to-one-row-per-statement path.
+	split := wb.SplitStatements(1)
+	require.Len(t, split, 2)
+
+	for _, op := range split {
+		require.Equal(t, "cosmoshub-4.auth", op.Topic)
+		op.Release()
+	}
+}
+
+func BenchmarkWritebackOp_SplitStatements(b *testing.B) {
+	data := make([]models.DatabaseEntrier, 100)
+	for i := range data {
+		data[i] = models.AuthRow{
+			TracelistenerDatabaseRow: models.TracelistenerDatabaseRow{ChainName: "chain"},
+			Address:                  "address",
+			SequenceNumber:           1,
+			AccountNumber:            1,
+		}
+	}
+
+	wb := tracelistener.WritebackOp{DatabaseExec: "statement", Data: data}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		split := wb.SplitStatements(4)
+		for _, op := range split {
+			op.Release()
+		}
+	}
+}